@@ -3,31 +3,65 @@ package chained
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"github.com/golang/protobuf/proto"
-	"github.com/niclabs/tcrsa"
 	"github.com/sirupsen/logrus"
 	go_hotstuff "github.com/wjbbig/go-hotstuff"
 	"github.com/wjbbig/go-hotstuff/config"
 	"github.com/wjbbig/go-hotstuff/consensus"
+	"github.com/wjbbig/go-hotstuff/crypto"
+	"github.com/wjbbig/go-hotstuff/eventbus"
 	"github.com/wjbbig/go-hotstuff/logging"
 	pb "github.com/wjbbig/go-hotstuff/proto"
+	"github.com/wjbbig/go-hotstuff/sync"
+	"github.com/wjbbig/go-hotstuff/wal"
+	"path/filepath"
 	"strconv"
+	"time"
 )
 
+// fetchTimeout bounds how long update() waits for a missing parent block
+// before giving up on the proposal that referenced it.
+const fetchTimeout = 3 * time.Second
+
+// walMaxSegmentSize bounds how large a single WAL segment grows before a
+// new one is rotated in.
+const walMaxSegmentSize = 16 * 1024 * 1024
+
+// viewLagThreshold is how many views a NewView's QC can be ahead of the
+// local View before it is treated as "this replica is lagging" and a bulk
+// Downloader.Sync is kicked off instead of relying on per-block Fetcher
+// calls to eventually catch up.
+const viewLagThreshold = 50
+
+// syncTimeout bounds how long a single Downloader.Sync call is allowed to
+// run before it is abandoned.
+const syncTimeout = 30 * time.Second
+
 var logger *logrus.Logger
 
 func init() {
-	logger = logging.GetLogger()
+	logger = logging.Module("consensus/chained")
 }
 
 type ChainedHotStuff struct {
 	consensus.HotStuffImpl
-	genericQC *pb.QuorumCert
-	lockQC    *pb.QuorumCert
-	cancel    context.CancelFunc
+	genericQC  *pb.QuorumCert
+	lockQC     *pb.QuorumCert
+	cancel     context.CancelFunc
+	done       chan struct{}
+	fetcher    *sync.Fetcher
+	downloader *sync.Downloader
+	wal        wal.WAL
+	eventBus   *eventbus.EventBus
+	scheme     crypto.Scheme
 }
 
-func NewChainedHotStuff(id int, handleMethod func(string) string) *ChainedHotStuff {
+// NewChainedHotStuff builds a ChainedHotStuff for replica id. Any failure
+// that used to os.Exit the process via logger.Fatal is now returned instead,
+// so a replica that fails to initialize doesn't take the rest of a
+// multi-node test process down with it mid-view.
+func NewChainedHotStuff(id int, handleMethod func(string) string) (*ChainedHotStuff, error) {
 	msgEntrance := make(chan *pb.Msg)
 	chs := &ChainedHotStuff{}
 	chs.MsgEntrance = msgEntrance
@@ -39,7 +73,7 @@ func NewChainedHotStuff(id int, handleMethod func(string) string) *ChainedHotStu
 	genesisBlock := consensus.GenerateGenesisBlock()
 	err := chs.BlockStorage.Put(genesisBlock)
 	if err != nil {
-		logger.Fatal("generate genesis block failed")
+		return nil, fmt.Errorf("chained: generate genesis block failed: %w", err)
 	}
 	chs.genericQC = &pb.QuorumCert{
 		BlockHash: genesisBlock.Hash,
@@ -68,21 +102,82 @@ func NewChainedHotStuff(id int, handleMethod func(string) string) *ChainedHotStu
 	chs.BatchTimeChan.Init()
 
 	chs.CurExec = &consensus.CurProposal{
-		Node:          nil,
-		DocumentHash:  nil,
-		PrepareVote:   make([]*tcrsa.SigShare, 0),
-		HighQC:        make([]*pb.QuorumCert, 0),
+		Node:         nil,
+		DocumentHash: nil,
+		PrepareVote:  make([]crypto.PartialSignature, 0),
+		HighQC:       make([]*pb.QuorumCert, 0),
 	}
-	privateKey, err := go_hotstuff.ReadThresholdPrivateKeyFromFile(chs.GetSelfInfo().PrivateKey)
+	scheme, err := crypto.NewScheme(chs.Config.CryptoScheme, chs.GetSelfInfo().PrivateKey, chs.ID, int(chs.Config.F))
 	if err != nil {
-		logger.Fatal(err)
+		return nil, fmt.Errorf("chained: init crypto scheme: %w", err)
 	}
-	chs.Config.PrivateKey = privateKey
+	chs.scheme = scheme
 	chs.ProcessMethod = handleMethod
-	ctx, cancel := context.WithCancel(context.Background())
+	chs.fetcher = sync.NewFetcher(chs.ID, &chs.HotStuffImpl, chs.BlockStorage, fetchTimeout)
+	chs.downloader = sync.NewDownloader(&chs.HotStuffImpl, chs.BlockStorage, sync.GRPCRequester())
+	chs.eventBus = eventbus.NewEventBus()
+
+	walDir := filepath.Join("data", strconv.Itoa(id), "wal")
+	chs.wal, err = wal.NewFileWAL(walDir, walMaxSegmentSize)
+	if err != nil {
+		return nil, fmt.Errorf("chained: open wal: %w", err)
+	}
+	if err := chs.replayWAL(); err != nil {
+		return nil, fmt.Errorf("chained: replay wal: %w", err)
+	}
+	return chs, nil
+}
+
+// replayWAL reconstructs genericQC, lockQC, HighQC and View from the WAL
+// written before the previous shutdown or crash, so a restarted replica
+// never re-votes for a view it already decided and never forgets a QC it
+// had already locked in.
+func (chs *ChainedHotStuff) replayWAL() error {
+	records, err := chs.wal.ReadAll()
+	if err != nil {
+		return err
+	}
+	for _, rec := range records {
+		switch event := rec.Event.(type) {
+		case *pb.WALRecord_ViewEntered:
+			if event.ViewEntered.ViewNum > uint64(chs.View.ViewNum) {
+				chs.View = consensus.NewView(int(event.ViewEntered.ViewNum), 1)
+			}
+		case *pb.WALRecord_QcFormed:
+			switch event.QcFormed.Slot {
+			case "genericQC":
+				chs.genericQC = event.QcFormed.Qc
+			case "lockQC":
+				chs.lockQC = event.QcFormed.Qc
+			case "highQC":
+				chs.HighQC = event.QcFormed.Qc
+			}
+		}
+	}
+	return nil
+}
+
+// Start launches the receive loop bound to ctx. It returns once the loop
+// goroutine has been scheduled; callers should use Wait to block until the
+// loop actually exits. Calling Start more than once is not supported.
+func (chs *ChainedHotStuff) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
 	chs.cancel = cancel
-	go chs.receiveMsg(ctx)
-	return chs
+	chs.done = make(chan struct{})
+	go func() {
+		defer close(chs.done)
+		chs.receiveMsg(ctx)
+	}()
+	return nil
+}
+
+// Wait blocks until the receive loop started by Start has returned.
+func (chs *ChainedHotStuff) Wait() error {
+	if chs.done == nil {
+		return nil
+	}
+	<-chs.done
+	return nil
 }
 
 func (chs *ChainedHotStuff) receiveMsg(ctx context.Context) {
@@ -90,12 +185,38 @@ func (chs *ChainedHotStuff) receiveMsg(ctx context.Context) {
 		select {
 		case msg := <-chs.MsgEntrance:
 			chs.handleMsg(msg)
+		case <-chs.TimeChan.Chan():
+			chs.handleTimeout()
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
+// handleTimeout fires when TimeChan expires without the view completing in
+// time: the replica gives up on the current view, advances to the next one,
+// and restarts the timer for it. LeaderChanged is only published when
+// round-robin selection actually picked a different leader for the new
+// view, since GetLeader is keyed off the view number.
+func (chs *ChainedHotStuff) handleTimeout() {
+	oldLeader := chs.GetLeader()
+	chs.eventBus.Publish(eventbus.Event{
+		Type:       eventbus.TimeoutFired,
+		Attributes: map[string]string{"view": strconv.FormatUint(uint64(chs.View.ViewNum), 10)},
+	})
+	chs.enterView(uint64(chs.View.ViewNum) + 1)
+	if newLeader := chs.GetLeader(); newLeader != oldLeader {
+		chs.eventBus.Publish(eventbus.Event{
+			Type: eventbus.LeaderChanged,
+			Attributes: map[string]string{
+				"view":   strconv.FormatUint(uint64(chs.View.ViewNum), 10),
+				"leader": strconv.FormatUint(uint64(newLeader), 10),
+			},
+		})
+	}
+	chs.TimeChan.SoftStartTimer()
+}
+
 func (chs *ChainedHotStuff) handleMsg(msg *pb.Msg) {
 	switch msg.Payload.(type) {
 	case *pb.Msg_Request:
@@ -123,39 +244,136 @@ func (chs *ChainedHotStuff) handleMsg(msg *pb.Msg) {
 		}
 		break
 	case *pb.Msg_Prepare:
+		block := msg.GetPrepare().Block
+		chs.eventBus.Publish(eventbus.Event{
+			Type:       eventbus.ProposalReceived,
+			Attributes: map[string]string{"view": strconv.FormatUint(uint64(chs.View.ViewNum), 10)},
+			Data:       block,
+		})
+		// update() may walk into blockOfOrFetch, which blocks on a
+		// BlockResponse that can only ever arrive back through this same
+		// receiveMsg loop; running it inline here would deadlock the
+		// replica against its own fetch, so it runs on its own goroutine
+		// instead, matching the syncToView precedent below.
+		go chs.update(block)
 		break
 	case *pb.Msg_NewView:
+		qc := msg.GetNewView().Qc
+		chs.eventBus.Publish(eventbus.Event{
+			Type:       eventbus.NewView,
+			Attributes: map[string]string{"view": strconv.FormatUint(qc.ViewNum, 10)},
+			Data:       qc,
+		})
+		if chs.downloader.ShouldSync(uint64(chs.View.ViewNum), qc.ViewNum, viewLagThreshold) {
+			go chs.syncToView(qc)
+		}
 		break
+	case *pb.Msg_BlockResponse:
+		chs.fetcher.OnBlockResponse(msg.GetBlockResponse())
+		break
+	case *pb.Msg_BlockRequest:
+		chs.handleBlockRequest(msg.GetBlockRequest())
+		break
+	}
+}
+
+// handleBlockRequest answers a single-hash BlockRequest (the on-demand
+// parent-fetch path Fetcher.FetchParent drives) with a BlockResponse
+// Unicast back to the requester; it never handles the height-range form,
+// since bulk catch-up goes through the Sync gRPC service instead.
+func (chs *ChainedHotStuff) handleBlockRequest(req *pb.BlockRequest) {
+	if len(req.Hash) == 0 {
+		return
+	}
+	addr, ok := chs.GetNetworkInfo()[req.RequesterId]
+	if !ok {
+		logger.Warnf("[HOTSTUFF] block request from unknown replica %d", req.RequesterId)
+		return
+	}
+	block, err := chs.BlockStorage.Get(req.Hash)
+	if err != nil || block == nil {
+		return
+	}
+	chs.Unicast(addr, &pb.Msg{
+		Payload: &pb.Msg_BlockResponse{BlockResponse: &pb.BlockResponse{Blocks: []*pb.Block{block}}},
+	})
+}
+
+// syncToView is triggered when an incoming NewView's QC is far enough
+// ahead of our local View that walking Justify chains one fetch at a time
+// would take forever: it resolves the QC's block to learn the height the
+// rest of the cluster has reached, then hands every known peer to the
+// downloader, which spreads the contiguous range between our local height
+// and that one across them in batches.
+func (chs *ChainedHotStuff) syncToView(qc *pb.QuorumCert) {
+	ctx, cancel := context.WithTimeout(context.Background(), syncTimeout)
+	defer cancel()
+
+	localHash := chs.BlockStorage.GetLastBlockHash()
+	localBlock, err := chs.BlockStorage.Get(localHash)
+	if err != nil || localBlock == nil {
+		logger.Errorf("[HOTSTUFF] sync: failed to resolve local head: %v", err)
+		return
+	}
+
+	remoteBlock, err := chs.blockOfOrFetch(qc)
+	if err != nil {
+		logger.Errorf("[HOTSTUFF] sync: failed to resolve remote QC block: %v", err)
+		return
+	}
+	if remoteBlock.Height <= localBlock.Height {
+		return
+	}
+
+	peers := make([]string, 0, len(chs.GetNetworkInfo()))
+	for _, addr := range chs.GetNetworkInfo() {
+		peers = append(peers, addr)
+	}
+	logger.Infof("[HOTSTUFF] sync: catching up from height %d to %d", localBlock.Height, remoteBlock.Height)
+	if err := chs.downloader.Sync(ctx, peers, localBlock.Height+1, remoteBlock.Height); err != nil {
+		logger.Errorf("[HOTSTUFF] sync: catch-up failed: %v", err)
 	}
 }
 
 func (chs *ChainedHotStuff) update(block *pb.Block) {
 	// block = b*, block1 = b'', block2 = b', block3 = b
-	block1, err := chs.BlockStorage.BlockOf(block.Justify)
+	block1, err := chs.blockOfOrFetch(block.Justify)
 	if err != nil {
-		logger.Fatal(err)
+		logger.Errorf("[HOTSTUFF] failed to resolve justify chain for block %x: %v", block.Hash, err)
+		return
 	}
 	if block1 == nil || block1.Committed {
 		return
 	}
 	if bytes.Equal(block.ParentHash, block1.Hash) {
 		chs.genericQC = block.Justify
+		chs.writeWALQC("genericQC", block.Justify)
 	}
-	
-	block2, err := chs.BlockStorage.BlockOf(block1.Justify)
+	// HighQC tracks the newest QC this replica has seen, regardless of
+	// whether it extends the local chain, since batchEvent needs it to
+	// justify the next proposal even after a view change; persist it
+	// alongside genericQC so replayWAL can restore it instead of falling
+	// back to PrepareQC after a crash.
+	chs.HighQC = block.Justify
+	chs.writeWALQC("highQC", block.Justify)
+
+	block2, err := chs.blockOfOrFetch(block1.Justify)
 	if err != nil {
-		logger.Fatal(err)
+		logger.Errorf("[HOTSTUFF] failed to resolve justify chain for block %x: %v", block1.Hash, err)
+		return
 	}
 	if block2 == nil || block2.Committed {
 		return
 	}
 	if bytes.Equal(block.ParentHash, block1.Hash) && bytes.Equal(block1.ParentHash, block2.Hash) {
 		chs.lockQC = block1.Justify
+		chs.writeWALQC("lockQC", block1.Justify)
 	}
 
-	block3, err := chs.BlockStorage.BlockOf(block2.Justify)
+	block3, err := chs.blockOfOrFetch(block2.Justify)
 	if err != nil {
-		logger.Fatal(err)
+		logger.Errorf("[HOTSTUFF] failed to resolve justify chain for block %x: %v", block2.Hash, err)
+		return
 	}
 	if block3 == nil || block3.Committed {
 		return
@@ -167,10 +385,83 @@ func (chs *ChainedHotStuff) update(block *pb.Block) {
 	}
 }
 
-func (chs *ChainedHotStuff) SafeExit() {
-	chs.cancel()
+// blockOfOrFetch looks up the block a QuorumCert justifies, and if it is
+// missing from local storage, fetches it from the current view's leader instead of
+// failing the view: a replica that is merely a few blocks behind should
+// catch up rather than crash.
+func (chs *ChainedHotStuff) blockOfOrFetch(qc *pb.QuorumCert) (*pb.Block, error) {
+	block, err := chs.BlockStorage.BlockOf(qc)
+	if err == nil && block != nil {
+		return block, nil
+	}
+	src := chs.GetNetworkInfo()[chs.GetLeader()]
+	logger.Debugf("[HOTSTUFF] block %x missing locally, fetching from %s", qc.BlockHash, src)
+	return chs.fetcher.FetchParent(context.Background(), src, qc.BlockHash)
+}
+
+// SafeExit cancels the receive loop, waits up to drainTimeout for it to
+// exit, drains any requests still buffered in MsgEntrance so callers aren't
+// left blocked on a send, and finally flushes the block storage to disk.
+func (chs *ChainedHotStuff) SafeExit(drainTimeout time.Duration) error {
+	if chs.cancel != nil {
+		chs.cancel()
+	}
+	if chs.done != nil {
+		select {
+		case <-chs.done:
+		case <-time.After(drainTimeout):
+			logger.Warnf("[HOTSTUFF] receive loop did not exit within %s, draining anyway", drainTimeout)
+		}
+	}
+drain:
+	for {
+		select {
+		case <-chs.MsgEntrance:
+		default:
+			break drain
+		}
+	}
 	close(chs.MsgEntrance)
-	chs.BlockStorage.Close()
+	if err := chs.wal.Close(); err != nil {
+		logger.Errorf("[HOTSTUFF] failed to close wal: %v", err)
+	}
+	return chs.BlockStorage.Close()
+}
+
+// enterView advances the local view to viewNum, persisting the transition
+// to the WAL before it takes effect: a crash and replay must never forget
+// which view this replica had already moved into, or it could re-vote for
+// a view it had already left. Advancing to a lower or equal view is a
+// no-op, since replayWAL may have already caught chs.View up past viewNum.
+func (chs *ChainedHotStuff) enterView(viewNum uint64) {
+	if viewNum <= uint64(chs.View.ViewNum) {
+		return
+	}
+	if err := chs.wal.Write(&pb.WALRecord{
+		ViewNum: viewNum,
+		Event:   &pb.WALRecord_ViewEntered{ViewEntered: &pb.ViewEnteredEvent{ViewNum: viewNum}},
+	}); err != nil {
+		logger.Errorf("[HOTSTUFF] failed to write view entered to wal: %v", err)
+	}
+	chs.View = consensus.NewView(int(viewNum), 1)
+}
+
+// writeWALQC persists a newly formed genericQC/lockQC/highQC to the WAL
+// before it is allowed to influence a future vote.
+func (chs *ChainedHotStuff) writeWALQC(slot string, qc *pb.QuorumCert) {
+	if err := chs.wal.Write(&pb.WALRecord{
+		ViewNum: uint64(chs.View.ViewNum),
+		Event: &pb.WALRecord_QcFormed{
+			QcFormed: &pb.QCFormedEvent{Qc: qc, Slot: slot},
+		},
+	}); err != nil {
+		logger.Errorf("[HOTSTUFF] failed to write %s to wal: %v", slot, err)
+	}
+	chs.eventBus.Publish(eventbus.Event{
+		Type:       eventbus.QCFormed,
+		Attributes: map[string]string{"slot": slot, "view": strconv.FormatUint(uint64(chs.View.ViewNum), 10)},
+		Data:       qc,
+	})
 }
 
 func (chs *ChainedHotStuff) batchEvent(cmds []string) {
@@ -189,17 +480,69 @@ func (chs *ChainedHotStuff) batchEvent(cmds []string) {
 	prepareMsg := chs.Msg(pb.MsgType_PREPARE, node, chs.HighQC)
 	// vote self
 	marshal, _ := proto.Marshal(prepareMsg)
-	chs.CurExec.DocumentHash, _ = go_hotstuff.CreateDocumentHash(marshal, chs.Config.PublicKey)
-	partSig, _ := go_hotstuff.TSign(chs.CurExec.DocumentHash, chs.Config.PrivateKey, chs.Config.PublicKey)
+	chs.CurExec.DocumentHash = marshal
+	partSig, err := chs.scheme.Sign(marshal)
+	if err != nil {
+		logger.Errorf("[HOTSTUFF] failed to sign proposal: %v", err)
+		return
+	}
 	chs.CurExec.PrepareVote = append(chs.CurExec.PrepareVote, partSig)
+	if err := chs.wal.Write(&pb.WALRecord{
+		ViewNum: uint64(chs.View.ViewNum),
+		Event:   &pb.WALRecord_VoteSent{VoteSent: &pb.VoteSentEvent{BlockHash: node.Hash}},
+	}); err != nil {
+		logger.Errorf("[HOTSTUFF] failed to write vote to wal: %v", err)
+	}
+	chs.eventBus.Publish(eventbus.Event{
+		Type:       eventbus.VoteCast,
+		Attributes: map[string]string{"view": strconv.FormatUint(uint64(chs.View.ViewNum), 10)},
+		Data:       node,
+	})
 	// broadcast prepare msg
 	chs.Broadcast(prepareMsg)
 	chs.TimeChan.SoftStartTimer()
 }
 
+// Subscribe returns an eventbus subscription for the given query, so a
+// gRPC streaming handler (or any other external observer) can tail
+// consensus progress without polling.
+func (chs *ChainedHotStuff) Subscribe(query string) *eventbus.Subscription {
+	return chs.eventBus.Subscribe(query, eventbus.DefaultBufferSize, eventbus.DropOldest)
+}
+
+// Unsubscribe tears down a subscription returned by Subscribe, so a caller
+// that's done listening (e.g. a gRPC stream that just ended) doesn't leak
+// it in the bus forever.
+func (chs *ChainedHotStuff) Unsubscribe(sub *eventbus.Subscription) {
+	chs.eventBus.Unsubscribe(sub)
+}
+
+// GetBlockStorage exposes the replica's block store so a sync.Server can
+// serve range-based BlockRequests from it.
+func (chs *ChainedHotStuff) GetBlockStorage() sync.HeightBlockStore {
+	return chs.BlockStorage
+}
+
 func (chs *ChainedHotStuff) processProposal() {
 	// process proposal
 	go chs.ProcessProposal(chs.CurExec.Node.Commands)
 	// store block
 	chs.CurExec.Node.Committed = true
+	if err := chs.wal.Write(&pb.WALRecord{
+		ViewNum: uint64(chs.View.ViewNum),
+		Event: &pb.WALRecord_BlockCommitted{
+			BlockCommitted: &pb.BlockCommittedEvent{BlockHash: chs.CurExec.Node.Hash, Height: chs.CurExec.Node.Height},
+		},
+	}); err != nil {
+		logger.Errorf("[HOTSTUFF] failed to write commit to wal: %v", err)
+	}
+	if err := chs.wal.Compact(chs.CurExec.Node.Height); err != nil {
+		logger.Errorf("[HOTSTUFF] failed to compact wal: %v", err)
+	}
+	chs.eventBus.Publish(eventbus.Event{
+		Type:       eventbus.BlockCommitted,
+		Attributes: map[string]string{"height": strconv.FormatUint(chs.CurExec.Node.Height, 10)},
+		Data:       chs.CurExec.Node,
+	})
+	chs.enterView(uint64(chs.View.ViewNum) + 1)
 }
\ No newline at end of file
@@ -0,0 +1,163 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	bls "github.com/kilic/bls12-381"
+)
+
+// blsScheme aggregates one BLS12-381 signature per replica into a single,
+// constant-size AggregateSignature: unlike tcrsa, forming or verifying a QC
+// does not require a threshold-signing ceremony, just point addition.
+type blsScheme struct {
+	replicaID  uint32
+	privateKey *bls.Fr
+	publicKeys map[uint32]*bls.PointG1 // replicaID -> public key
+	g1         *bls.G1
+	g2         *bls.G2
+	pairing    *bls.Engine
+}
+
+// blsKeySet is the on-disk format of bls_keys.json in keyPath's directory: every
+// replica's public key plus, for this replica only, its private scalar.
+type blsKeySet struct {
+	ReplicaID  uint32            `json:"replicaId"`
+	PrivateKey string            `json:"privateKey"`
+	PublicKeys map[uint32]string `json:"publicKeys"`
+}
+
+// newBLSScheme loads bls_keys.json from the same directory as keyPath (the
+// config-file convention tcrsa's PrivateKey/PublicKey paths already use),
+// rather than treating keyPath itself as that directory.
+func newBLSScheme(keyPath string, replicaID uint32, threshold int) (Scheme, error) {
+	raw, err := ioutil.ReadFile(filepath.Join(filepath.Dir(keyPath), "bls_keys.json"))
+	if err != nil {
+		return nil, fmt.Errorf("crypto/bls: read key set: %w", err)
+	}
+	var keySet blsKeySet
+	if err := json.Unmarshal(raw, &keySet); err != nil {
+		return nil, fmt.Errorf("crypto/bls: parse key set: %w", err)
+	}
+
+	g1 := bls.NewG1()
+	g2 := bls.NewG2()
+
+	privateKey, err := fqFromHex(keySet.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("crypto/bls: parse private key: %w", err)
+	}
+	publicKeys := make(map[uint32]*bls.PointG1, len(keySet.PublicKeys))
+	for id, hexKey := range keySet.PublicKeys {
+		point, err := g1FromHex(g1, hexKey)
+		if err != nil {
+			return nil, fmt.Errorf("crypto/bls: parse public key for replica %d: %w", id, err)
+		}
+		publicKeys[id] = point
+	}
+
+	return &blsScheme{
+		replicaID:  replicaID,
+		privateKey: privateKey,
+		publicKeys: publicKeys,
+		g1:         g1,
+		g2:         g2,
+		pairing:    bls.NewEngine(),
+	}, nil
+}
+
+func hashToCurve(g2 *bls.G2, document []byte) *bls.PointG2 {
+	sum := sha256.Sum256(document)
+	return g2.MapToCurve(sum[:])
+}
+
+func (s *blsScheme) Sign(document []byte) (PartialSignature, error) {
+	point := hashToCurve(s.g2, document)
+	s.g2.MulScalar(point, point, s.privateKey)
+	return PartialSignature(s.g2.ToCompressed(point)), nil
+}
+
+func (s *blsScheme) VerifyPartial(document []byte, replicaID uint32, sig PartialSignature) error {
+	pub, ok := s.publicKeys[replicaID]
+	if !ok {
+		return fmt.Errorf("crypto/bls: no public key for replica %d", replicaID)
+	}
+	return s.verify(document, sig, pub)
+}
+
+// VerifyAggregate sums exactly the public keys named by signerIDs (the
+// QuorumCert's SignerIds) to get the aggregate public key to check sig
+// against: BLS aggregate signatures are only valid against the sum of the
+// keys that actually signed, so in the normal 2f+1-of-3f+1 case this must
+// never default to summing every known key.
+func (s *blsScheme) VerifyAggregate(document []byte, signerIDs []uint32, sig AggregateSignature) error {
+	if len(signerIDs) == 0 {
+		return fmt.Errorf("crypto/bls: no signer IDs given to verify against")
+	}
+	var aggPub *bls.PointG1
+	for _, id := range signerIDs {
+		pub, ok := s.publicKeys[id]
+		if !ok {
+			return fmt.Errorf("crypto/bls: no public key for replica %d", id)
+		}
+		if aggPub == nil {
+			aggPub = s.g1.New()
+			s.g1.Copy(aggPub, pub)
+			continue
+		}
+		s.g1.Add(aggPub, aggPub, pub)
+	}
+	return s.verify(document, PartialSignature(sig), aggPub)
+}
+
+func (s *blsScheme) verify(document []byte, sig PartialSignature, pub *bls.PointG1) error {
+	point, err := s.g2.FromCompressed(sig)
+	if err != nil {
+		return fmt.Errorf("crypto/bls: decompress signature: %w", err)
+	}
+	msg := hashToCurve(s.g2, document)
+	s.pairing.Reset()
+	s.pairing.AddPair(pub, msg)
+	s.pairing.AddPairInv(s.g1.One(), point)
+	if !s.pairing.Check() {
+		return fmt.Errorf("crypto/bls: signature verification failed")
+	}
+	return nil
+}
+
+// Aggregate sums the shares into a single point; it doesn't need signerIDs
+// itself (point addition is commutative and order-independent) but takes
+// it to satisfy Aggregator, since callers must still thread the IDs through
+// to the QuorumCert for VerifyAggregate to use later.
+func (s *blsScheme) Aggregate(document []byte, signerIDs []uint32, shares []PartialSignature) (AggregateSignature, error) {
+	if len(shares) == 0 {
+		return nil, fmt.Errorf("crypto/bls: no shares to aggregate")
+	}
+	agg, err := s.g2.FromCompressed(shares[0])
+	if err != nil {
+		return nil, fmt.Errorf("crypto/bls: decompress share: %w", err)
+	}
+	for _, share := range shares[1:] {
+		point, err := s.g2.FromCompressed(share)
+		if err != nil {
+			return nil, fmt.Errorf("crypto/bls: decompress share: %w", err)
+		}
+		s.g2.Add(agg, agg, point)
+	}
+	return AggregateSignature(s.g2.ToCompressed(agg)), nil
+}
+
+func fqFromHex(hexStr string) (*bls.Fr, error) {
+	fr := new(bls.Fr)
+	if err := fr.FromString(hexStr); err != nil {
+		return nil, err
+	}
+	return fr, nil
+}
+
+func g1FromHex(g1 *bls.G1, hexStr string) (*bls.PointG1, error) {
+	return g1.FromString(hexStr)
+}
@@ -0,0 +1,51 @@
+// Package crypto abstracts the threshold/aggregate signature scheme used to
+// form quorum certificates, so the consensus packages depend only on
+// PartialSignature/AggregateSignature and never on a specific scheme's
+// types (tcrsa.SigShare, a BLS point, ...). This lets a cluster pick
+// whichever scheme suits it best, e.g. BLS for its much smaller, much
+// cheaper-to-verify aggregate signatures, without touching consensus code.
+package crypto
+
+// PartialSignature is one replica's share of a signature over a document
+// hash. It is opaque outside the Scheme that produced it.
+type PartialSignature []byte
+
+// AggregateSignature is the combination of a quorum of PartialSignatures,
+// carried as the opaque Signature blob on a QuorumCert.
+type AggregateSignature []byte
+
+// Signer produces a PartialSignature over document on behalf of one
+// replica.
+type Signer interface {
+	Sign(document []byte) (PartialSignature, error)
+}
+
+// Verifier checks a PartialSignature or AggregateSignature against a
+// document. VerifyAggregate takes the replica IDs that actually
+// contributed to sig (a QuorumCert's SignerIds) so a verifier never has to
+// guess, and never has to assume, which subset of the cluster signed: for
+// schemes like BLS whose aggregate public key depends on exactly which
+// keys were summed, guessing the wrong subset (e.g. "everyone") would
+// reject every QC that wasn't signed by the full cluster.
+type Verifier interface {
+	VerifyPartial(document []byte, replicaID uint32, sig PartialSignature) error
+	VerifyAggregate(document []byte, signerIDs []uint32, sig AggregateSignature) error
+}
+
+// Aggregator combines a quorum of PartialSignatures into the
+// AggregateSignature a QuorumCert carries. signerIDs must be the replica ID
+// each entry in shares came from, in the same order, so the scheme can
+// record (or, for BLS, doesn't even need to record, since the IDs are
+// carried on the QuorumCert itself) who contributed.
+type Aggregator interface {
+	Aggregate(document []byte, signerIDs []uint32, shares []PartialSignature) (AggregateSignature, error)
+}
+
+// Scheme bundles the three roles a QC's lifecycle needs: the local replica
+// signs its vote, every replica can verify a share or the final
+// certificate, and the leader aggregates the quorum's shares.
+type Scheme interface {
+	Signer
+	Verifier
+	Aggregator
+}
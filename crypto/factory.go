@@ -0,0 +1,30 @@
+package crypto
+
+import "fmt"
+
+// Scheme names accepted by config.HotStuffConfig.CryptoScheme.
+const (
+	TCRSA           = "tcrsa"
+	BLS12381        = "bls12-381"
+	Ed25519Multisig = "ed25519-multisig"
+)
+
+// NewScheme constructs the Scheme named by kind, loading whatever key
+// material it needs for replicaID. keyPath is the same private-key-file
+// path tcrsa has always been configured with (HotStuffConfig.PrivateKey);
+// BLS and ed25519 locate their own key set file in keyPath's directory
+// rather than requiring a separate directory-shaped config value. An empty
+// kind keeps the historical default (tcrsa) so existing configs and key
+// files keep working unchanged.
+func NewScheme(kind string, keyPath string, replicaID uint32, threshold int) (Scheme, error) {
+	switch kind {
+	case "", TCRSA:
+		return newTCRSAScheme(keyPath)
+	case BLS12381:
+		return newBLSScheme(keyPath, replicaID, threshold)
+	case Ed25519Multisig:
+		return newEd25519Scheme(keyPath, replicaID, threshold)
+	default:
+		return nil, fmt.Errorf("crypto: unknown scheme %q", kind)
+	}
+}
@@ -0,0 +1,124 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+
+	"github.com/niclabs/tcrsa"
+	go_hotstuff "github.com/wjbbig/go-hotstuff"
+)
+
+// tcrsaScheme is the original threshold-RSA scheme, moved behind the Scheme
+// interface unchanged: it still shells out to the niclabs/tcrsa library and
+// the key files under config.HotStuffConfig.PrivateKey/PublicKey.
+type tcrsaScheme struct {
+	privateKey *tcrsa.KeyShare
+	publicKey  *tcrsa.KeyMeta
+}
+
+func newTCRSAScheme(keyPath string) (Scheme, error) {
+	privateKey, err := go_hotstuff.ReadThresholdPrivateKeyFromFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("crypto/tcrsa: read private key: %w", err)
+	}
+	publicKey, err := go_hotstuff.ReadThresholdPublicKeyFromFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("crypto/tcrsa: read public key: %w", err)
+	}
+	return &tcrsaScheme{privateKey: privateKey, publicKey: publicKey}, nil
+}
+
+func (s *tcrsaScheme) Sign(document []byte) (PartialSignature, error) {
+	hash, err := go_hotstuff.CreateDocumentHash(document, s.publicKey)
+	if err != nil {
+		return nil, err
+	}
+	share, err := go_hotstuff.TSign(hash, s.privateKey, s.publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return encodeSigShare(share)
+}
+
+func (s *tcrsaScheme) VerifyPartial(document []byte, replicaID uint32, sig PartialSignature) error {
+	hash, err := go_hotstuff.CreateDocumentHash(document, s.publicKey)
+	if err != nil {
+		return err
+	}
+	share, err := decodeSigShare(sig)
+	if err != nil {
+		return err
+	}
+	return share.Verify(hash, s.publicKey)
+}
+
+// VerifyAggregate ignores signerIDs: a tcrsa signature is valid against the
+// single cluster public key regardless of which k of n replicas
+// contributed the shares that were joined to produce it.
+func (s *tcrsaScheme) VerifyAggregate(document []byte, signerIDs []uint32, sig AggregateSignature) error {
+	hash, err := go_hotstuff.CreateDocumentHash(document, s.publicKey)
+	if err != nil {
+		return err
+	}
+	signature, err := decodeSignature(sig)
+	if err != nil {
+		return err
+	}
+	return signature.Verify(hash, s.publicKey)
+}
+
+// Aggregate ignores signerIDs for the same reason VerifyAggregate does: a
+// tcrsa.SigShare already carries its own index, so Join doesn't need to be
+// told which replicas they came from.
+func (s *tcrsaScheme) Aggregate(document []byte, signerIDs []uint32, shares []PartialSignature) (AggregateSignature, error) {
+	hash, err := go_hotstuff.CreateDocumentHash(document, s.publicKey)
+	if err != nil {
+		return nil, err
+	}
+	sigShares := make(tcrsa.SigShareList, 0, len(shares))
+	for _, share := range shares {
+		decoded, err := decodeSigShare(share)
+		if err != nil {
+			return nil, err
+		}
+		sigShares = append(sigShares, decoded)
+	}
+	signature, err := sigShares.Join(hash, s.publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("crypto/tcrsa: join shares: %w", err)
+	}
+	return encodeSignature(signature)
+}
+
+func encodeSigShare(share *tcrsa.SigShare) (PartialSignature, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(share); err != nil {
+		return nil, fmt.Errorf("crypto/tcrsa: encode share: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeSigShare(sig PartialSignature) (*tcrsa.SigShare, error) {
+	share := &tcrsa.SigShare{}
+	if err := gob.NewDecoder(bytes.NewReader(sig)).Decode(share); err != nil {
+		return nil, fmt.Errorf("crypto/tcrsa: decode share: %w", err)
+	}
+	return share, nil
+}
+
+func encodeSignature(signature *tcrsa.Signature) (AggregateSignature, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(signature); err != nil {
+		return nil, fmt.Errorf("crypto/tcrsa: encode signature: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeSignature(sig AggregateSignature) (*tcrsa.Signature, error) {
+	signature := &tcrsa.Signature{}
+	if err := gob.NewDecoder(bytes.NewReader(sig)).Decode(signature); err != nil {
+		return nil, fmt.Errorf("crypto/tcrsa: decode signature: %w", err)
+	}
+	return signature, nil
+}
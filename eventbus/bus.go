@@ -0,0 +1,135 @@
+package eventbus
+
+import (
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"github.com/wjbbig/go-hotstuff/logging"
+)
+
+var logger *logrus.Logger
+
+func init() {
+	logger = logging.Module("eventbus")
+}
+
+// SlowConsumerPolicy decides what happens when a subscriber's buffered
+// channel is full at publish time.
+type SlowConsumerPolicy int
+
+const (
+	// DropOldest evicts the subscriber's oldest buffered event to make room
+	// for the new one, favoring liveness over completeness.
+	DropOldest SlowConsumerPolicy = iota
+	// Disconnect closes the subscription, favoring completeness: a
+	// subscriber that can't keep up is told to reconnect and catch up some
+	// other way rather than silently missing events.
+	Disconnect
+)
+
+// DefaultBufferSize is used when a subscriber doesn't specify one.
+const DefaultBufferSize = 64
+
+// Subscription is handed to a caller of Subscribe; it delivers events
+// matching the subscription's query until Unsubscribe is called or the bus
+// disconnects it under Disconnect policy.
+type Subscription struct {
+	out    chan Event
+	query  Query
+	policy SlowConsumerPolicy
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// Events returns the channel events are delivered on. It is closed when the
+// subscription is canceled or disconnected.
+func (s *Subscription) Events() <-chan Event {
+	return s.out
+}
+
+func (s *Subscription) deliver(event Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.out <- event:
+		return
+	default:
+	}
+	switch s.policy {
+	case DropOldest:
+		select {
+		case <-s.out:
+		default:
+		}
+		select {
+		case s.out <- event:
+		default:
+		}
+	case Disconnect:
+		s.closeLocked()
+	}
+}
+
+func (s *Subscription) closeLocked() {
+	if s.closed {
+		return
+	}
+	s.closed = true
+	close(s.out)
+}
+
+// EventBus fans published events out to every subscriber whose query
+// matches.
+type EventBus struct {
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+// NewEventBus creates an empty bus.
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[*Subscription]struct{})}
+}
+
+// Subscribe registers a new subscription for events matching query, with a
+// buffered channel of the given size and the given slow-consumer policy.
+func (b *EventBus) Subscribe(query string, bufferSize int, policy SlowConsumerPolicy) *Subscription {
+	if bufferSize <= 0 {
+		bufferSize = DefaultBufferSize
+	}
+	sub := &Subscription{
+		out:    make(chan Event, bufferSize),
+		query:  ParseQuery(query),
+		policy: policy,
+	}
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+	return sub
+}
+
+// Unsubscribe stops delivery to sub and closes its channel.
+func (b *EventBus) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	delete(b.subs, sub)
+	b.mu.Unlock()
+	sub.mu.Lock()
+	sub.closeLocked()
+	sub.mu.Unlock()
+}
+
+// Publish delivers event to every current subscriber whose query matches.
+func (b *EventBus) Publish(event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subs {
+		if !sub.query.Matches(event) {
+			continue
+		}
+		sub.deliver(event)
+	}
+	logger.Debugf("[EVENTBUS] published %s event", event.Type)
+}
@@ -0,0 +1,27 @@
+// Package eventbus lets application code and external observers (block
+// explorers, monitors) watch consensus progress without polling, in the
+// shape of tendermint's internal/eventbus: typed events are published to a
+// bus and delivered to subscribers whose query matches.
+package eventbus
+
+// Type identifies the kind of event published on the bus.
+type Type string
+
+const (
+	NewView          Type = "NewView"
+	ProposalReceived Type = "ProposalReceived"
+	VoteCast         Type = "VoteCast"
+	QCFormed         Type = "QCFormed"
+	BlockCommitted   Type = "BlockCommitted"
+	TimeoutFired     Type = "TimeoutFired"
+	LeaderChanged    Type = "LeaderChanged"
+)
+
+// Event is published on the bus. Attributes are the fields a query can
+// filter on, e.g. {"view": "12"} or {"leader": "3"}; Data carries the
+// payload handed to subscribers verbatim.
+type Event struct {
+	Type       Type
+	Attributes map[string]string
+	Data       interface{}
+}
@@ -0,0 +1,67 @@
+package eventbus
+
+import (
+	"encoding/json"
+	"fmt"
+
+	pb "github.com/wjbbig/go-hotstuff/proto"
+)
+
+// Subscriber is satisfied by anything that can hand back a bus subscription
+// for a query string, e.g. consensus/chained.ChainedHotStuff. Depending on
+// this instead of *EventBus directly lets the gRPC bridge sit in front of
+// a consensus implementation without that implementation exposing its
+// *EventBus field. Unsubscribe lets the bridge tear the subscription back
+// down once its stream ends, instead of leaking it in the bus forever.
+type Subscriber interface {
+	Subscribe(query string) *Subscription
+	Unsubscribe(sub *Subscription)
+}
+
+// eventServer implements the generated EventsServer interface by bridging
+// a gRPC stream to a bus subscription: one subscription per RPC call, torn
+// down when the stream ends.
+type eventServer struct {
+	pb.UnimplementedEventsServer
+	sub Subscriber
+}
+
+// NewEventServer returns a pb.EventsServer backed by sub, for registering
+// against a grpc.Server with pb.RegisterEventsServer.
+func NewEventServer(sub Subscriber) pb.EventsServer {
+	return &eventServer{sub: sub}
+}
+
+// SubscribeEvents streams every event matching req.Query to the caller
+// until the stream's context is canceled or the subscription is
+// disconnected for falling behind. The subscription is always torn back
+// down before returning, whichever of those ends the loop, so a client
+// that drops the stream doesn't leak it in the bus or leave this goroutine
+// parked on an abandoned channel.
+func (s *eventServer) SubscribeEvents(req *pb.SubscribeRequest, stream pb.Events_SubscribeEventsServer) error {
+	subscription := s.sub.Subscribe(req.Query)
+	defer s.sub.Unsubscribe(subscription)
+
+	ctx := stream.Context()
+	for {
+		select {
+		case event, ok := <-subscription.Events():
+			if !ok {
+				return nil
+			}
+			data, err := json.Marshal(event.Data)
+			if err != nil {
+				return fmt.Errorf("eventbus: marshal event data: %w", err)
+			}
+			if err := stream.Send(&pb.Event{
+				Type:       string(event.Type),
+				Attributes: event.Attributes,
+				Data:       data,
+			}); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package eventbus
+
+import "strings"
+
+// Query is a minimal filter language: a comma-separated list of
+// attribute=value terms, all of which must match (logical AND). The
+// special query "*" matches every event. Terms may also filter on the
+// reserved "type" attribute, e.g. "type=QCFormed,view=12".
+type Query struct {
+	terms map[string]string
+}
+
+// ParseQuery compiles a query string into a Query.
+func ParseQuery(query string) Query {
+	q := Query{terms: make(map[string]string)}
+	query = strings.TrimSpace(query)
+	if query == "" || query == "*" {
+		return q
+	}
+	for _, term := range strings.Split(query, ",") {
+		kv := strings.SplitN(term, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		q.terms[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return q
+}
+
+// Matches reports whether event satisfies every term in the query.
+func (q Query) Matches(event Event) bool {
+	for key, want := range q.terms {
+		if key == "type" {
+			if string(event.Type) != want {
+				return false
+			}
+			continue
+		}
+		if event.Attributes[key] != want {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,27 @@
+package logging
+
+import (
+	"context"
+
+	pb "github.com/wjbbig/go-hotstuff/proto"
+)
+
+// AdminService implements pb.AdminServer, letting an operator change a
+// module's log level at runtime over gRPC instead of editing Config and
+// restarting the node.
+type AdminService struct {
+	pb.UnimplementedAdminServer
+}
+
+// NewAdminService returns an AdminService ready to be registered on a
+// grpc.Server alongside the node's other services.
+func NewAdminService() *AdminService {
+	return &AdminService{}
+}
+
+func (s *AdminService) SetLevel(ctx context.Context, req *pb.SetLevelRequest) (*pb.SetLevelResponse, error) {
+	if err := SetLevel(req.Module, req.Level); err != nil {
+		return nil, err
+	}
+	return &pb.SetLevelResponse{}, nil
+}
@@ -1,30 +1,38 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"github.com/wjbbig/go-hotstuff/factory"
 	"github.com/wjbbig/go-hotstuff/consensus"
+	"github.com/wjbbig/go-hotstuff/eventbus"
 	"github.com/wjbbig/go-hotstuff/logging"
 	"github.com/wjbbig/go-hotstuff/proto"
+	"github.com/wjbbig/go-hotstuff/sync"
 	"google.golang.org/grpc"
+	"io/ioutil"
 	"net"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 )
 
+const drainTimeout = 5 * time.Second
+
 var (
 	id          int
 	networkType string
-	logger      = logging.GetLogger()
-	//sigChan     chan os.Signal
-	//done        chan bool
+	logConfig   string
+	logger      = logging.Module("server")
 )
 
 func init() {
-	//sigChan = make(chan os.Signal, 1)
-	//signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	//done = make(chan bool)
 	flag.IntVar(&id, "id", 0, "node id")
 	flag.StringVar(&networkType, "type", "basic", "which type of network you want to create.  basic/chained/event-driven")
+	flag.StringVar(&logConfig, "log-config", "", "path to a logging.Config JSON file; empty keeps the default (stderr, info)")
 }
 
 func main() {
@@ -33,11 +41,24 @@ func main() {
 		flag.Usage()
 		return
 	}
+	if logConfig != "" {
+		if err := loadLogConfig(logConfig); err != nil {
+			logger.Fatalf("[HOTSTUFF] Failed to load log config: %v", err)
+		}
+	}
+
 	// create grpc server
 	rpcServer := grpc.NewServer()
 
 	hotStuffService := consensus.NewHotStuffService(factory.HotStuffFactory(networkType, id))
 	proto.RegisterBasicHotStuffServer(rpcServer, hotStuffService)
+	proto.RegisterAdminServer(rpcServer, logging.NewAdminService())
+	if sub, ok := hotStuffService.GetImpl().(eventbus.Subscriber); ok {
+		proto.RegisterEventsServer(rpcServer, eventbus.NewEventServer(sub))
+	}
+	if bs, ok := hotStuffService.GetImpl().(blockStoreProvider); ok {
+		proto.RegisterSyncServer(rpcServer, sync.NewServer(bs.GetBlockStorage()))
+	}
 	// get node port
 	info := hotStuffService.GetImpl().GetSelfInfo()
 	port := info.Address[strings.Index(info.Address, ":"):]
@@ -48,14 +69,59 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
-	// close goroutine,db connection and delete db file safe when exiting
-	//go func() {
-	//	<-sigChan
-	//	logger.Info("[HOTSTUFF] Shut down...")
-	//	hotStuffService.GetImpl().SafeExit()
-	//	done <- true
-	//}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := hotStuffService.GetImpl().Start(ctx); err != nil {
+		logger.Fatalf("[HOTSTUFF] Failed to start consensus: %v", err)
+	}
+
+	// close goroutine, db connection and delete db file safely when exiting
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		sig := <-sigChan
+		logger.Infof("[HOTSTUFF] Received %v, shutting down...", sig)
+		cancel()
+		rpcServer.GracefulStop()
+		if err := hotStuffService.GetImpl().SafeExit(drainTimeout); err != nil {
+			logger.Errorf("[HOTSTUFF] Error during shutdown: %v", err)
+		}
+	}()
+
 	// start server
-	rpcServer.Serve(listen)
-	//<-done
+	if err := rpcServer.Serve(listen); err != nil {
+		logger.Errorf("[HOTSTUFF] gRPC server stopped: %v", err)
+	}
+	if err := hotStuffService.GetImpl().Wait(); err != nil {
+		logger.Errorf("[HOTSTUFF] Error waiting for consensus loop to exit: %v", err)
+	}
+	// rpcServer.Serve only returns once GracefulStop has been called, but
+	// SafeExit may still be mid-drain at that point; wait for the shutdown
+	// goroutine to actually finish before main returns.
+	<-shutdownDone
+}
+
+// blockStoreProvider is satisfied by anything that exposes the backing
+// block store a sync.Server can read range requests from, e.g.
+// consensus/chained.ChainedHotStuff.
+type blockStoreProvider interface {
+	GetBlockStorage() sync.HeightBlockStore
+}
+
+// loadLogConfig reads a logging.Config from a JSON file and installs it,
+// so operators can redirect output, bound rotation, and set per-module
+// levels without touching flags for every knob.
+func loadLogConfig(path string) error {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var cfg logging.Config
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return err
+	}
+	logging.Configure(cfg)
+	return nil
 }
@@ -0,0 +1,133 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	pb "github.com/wjbbig/go-hotstuff/proto"
+)
+
+// memBlockStore is a minimal, goroutine-safe BlockStore for tests, keyed by
+// hash (tests use "block-<height>" hashes, see heightHash, for readability).
+type memBlockStore struct {
+	mu     sync.Mutex
+	blocks map[string]*pb.Block
+}
+
+func newMemBlockStore() *memBlockStore {
+	return &memBlockStore{blocks: make(map[string]*pb.Block)}
+}
+
+func (s *memBlockStore) Put(block *pb.Block) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blocks[string(block.Hash)] = block
+	return nil
+}
+
+func (s *memBlockStore) Get(hash []byte) (*pb.Block, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	block, ok := s.blocks[string(hash)]
+	if !ok {
+		return nil, fmt.Errorf("block %x not found", hash)
+	}
+	return block, nil
+}
+
+func (s *memBlockStore) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.blocks)
+}
+
+func heightHash(height uint64) []byte {
+	return []byte(fmt.Sprintf("block-%d", height))
+}
+
+// fakePeer serves blocks [0, maxHeight] for any requested range, clamped to
+// what it actually has, simulating a healthy cluster member.
+func fakePeer(maxHeight uint64) func(ctx context.Context, peerAddr string, start, end uint64) (*pb.BlockResponse, error) {
+	return func(ctx context.Context, peerAddr string, start, end uint64) (*pb.BlockResponse, error) {
+		if end > maxHeight {
+			end = maxHeight
+		}
+		resp := &pb.BlockResponse{}
+		for h := start; h <= end; h++ {
+			resp.Blocks = append(resp.Blocks, &pb.Block{Hash: heightHash(h), Height: h})
+		}
+		return resp, nil
+	}
+}
+
+// TestDownloaderCatchesUpManyViewsAhead simulates a node that boots with an
+// empty BlockStorage against a live cluster many views (and therefore many
+// blocks) ahead of it, and checks that Sync pulls the full contiguous range
+// in batches rather than requiring one fetch per block.
+func TestDownloaderCatchesUpManyViewsAhead(t *testing.T) {
+	const remoteHeight = 500 // several multiples of batchSize, to force pipelining across batches
+	store := newMemBlockStore()
+	d := NewDownloader(nil, store, fakePeer(remoteHeight))
+
+	if !d.ShouldSync(0, remoteHeight, viewLagThresholdForTest) {
+		t.Fatalf("expected ShouldSync(0, %d) to report this replica is lagging", remoteHeight)
+	}
+
+	if err := d.Sync(context.Background(), []string{"peer-1:7000"}, 1, remoteHeight); err != nil {
+		t.Fatalf("Sync returned error: %v", err)
+	}
+
+	if got := store.count(); got != remoteHeight {
+		t.Fatalf("expected %d blocks stored after catch-up, got %d", remoteHeight, got)
+	}
+	for h := uint64(1); h <= remoteHeight; h++ {
+		if _, err := store.Get(heightHash(h)); err != nil {
+			t.Fatalf("missing block at height %d after catch-up: %v", h, err)
+		}
+	}
+}
+
+// viewLagThresholdForTest mirrors the threshold ChainedHotStuff uses in
+// production; kept local so this package's tests don't depend on the
+// consensus/chained package.
+const viewLagThresholdForTest = 50
+
+func TestDownloaderResyncOfSameRangeKeepsDistinctBlocks(t *testing.T) {
+	store := newMemBlockStore()
+	d := NewDownloader(nil, store, fakePeer(10))
+
+	if err := d.Sync(context.Background(), []string{"peer-1:7000"}, 1, 10); err != nil {
+		t.Fatalf("Sync returned error: %v", err)
+	}
+	if err := d.Sync(context.Background(), []string{"peer-1:7000"}, 1, 10); err != nil {
+		t.Fatalf("re-running Sync over an already-synced range returned error: %v", err)
+	}
+	if got := store.count(); got != 10 {
+		t.Fatalf("expected 10 distinct blocks after overlapping Sync calls, got %d", got)
+	}
+}
+
+func TestDownloaderPenalizesFailingPeer(t *testing.T) {
+	d := NewDownloader(nil, newMemBlockStore(), fakePeer(1))
+
+	before := d.scoreFor("flaky:7000").failures
+	d.penalize("flaky:7000")
+	if got := d.scoreFor("flaky:7000").failures; got != before+1 {
+		t.Fatalf("expected failures to increase by 1, got %d -> %d", before, got)
+	}
+	d.reward("flaky:7000")
+	if got := d.scoreFor("flaky:7000").failures; got != before {
+		t.Fatalf("expected reward to undo the penalty, got %d want %d", got, before)
+	}
+
+	best := d.bestPeer([]string{"flaky:7000", "healthy:7000"})
+	if best != "healthy:7000" {
+		d.penalize("flaky:7000")
+		best = d.bestPeer([]string{"flaky:7000", "healthy:7000"})
+		if best != "healthy:7000" {
+			t.Fatalf("expected bestPeer to avoid the peer with more failures, got %q", best)
+		}
+	}
+}
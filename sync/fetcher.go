@@ -0,0 +1,113 @@
+// Package sync implements block catch-up for replicas that are missing
+// ancestors of an in-flight proposal or have fallen behind the rest of the
+// cluster, modeled on the fetcher/downloader split used by go-ethereum:
+// Fetcher resolves individual blocks needed right now for on-line
+// propagation, while Downloader (see downloader.go) performs bulk,
+// range-based catch-up when a replica is many views behind.
+package sync
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/wjbbig/go-hotstuff/logging"
+	pb "github.com/wjbbig/go-hotstuff/proto"
+)
+
+var logger *logrus.Logger
+
+func init() {
+	logger = logging.Module("sync")
+}
+
+// PeerSet is the subset of network operations the sync subsystem needs from
+// a HotStuffImpl: sending a point-to-point request and enumerating replicas.
+type PeerSet interface {
+	Unicast(addr string, msg *pb.Msg)
+	GetNetworkInfo() map[uint32]string
+}
+
+// BlockStore is satisfied by BlockStorage; it lets the fetcher persist and
+// look up blocks it retrieves from peers.
+type BlockStore interface {
+	Put(block *pb.Block) error
+	Get(hash []byte) (*pb.Block, error)
+}
+
+// Fetcher resolves a single missing block on demand, e.g. when update()
+// walks a Justify chain and hits a parent it has never seen.
+type Fetcher struct {
+	selfID  uint32
+	peers   PeerSet
+	storage BlockStore
+	timeout time.Duration
+
+	mu      sync.Mutex
+	pending map[string][]chan *pb.Block // hex(hash) -> waiters
+}
+
+// NewFetcher creates a Fetcher that waits up to timeout for a peer to
+// reply to any one request. selfID is stamped on every BlockRequest as
+// RequesterId so the peer answering it knows who to Unicast the
+// BlockResponse back to.
+func NewFetcher(selfID uint32, peers PeerSet, storage BlockStore, timeout time.Duration) *Fetcher {
+	return &Fetcher{
+		selfID:  selfID,
+		peers:   peers,
+		storage: storage,
+		timeout: timeout,
+		pending: make(map[string][]chan *pb.Block),
+	}
+}
+
+// FetchParent requests the block identified by hash from the replica at
+// srcAddr and blocks until it arrives, is found locally, ctx is canceled, or
+// the fetcher's timeout elapses.
+func (f *Fetcher) FetchParent(ctx context.Context, srcAddr string, hash []byte) (*pb.Block, error) {
+	if block, err := f.storage.Get(hash); err == nil && block != nil {
+		return block, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+
+	key := hex.EncodeToString(hash)
+	wait := make(chan *pb.Block, 1)
+	f.mu.Lock()
+	f.pending[key] = append(f.pending[key], wait)
+	f.mu.Unlock()
+
+	f.peers.Unicast(srcAddr, &pb.Msg{
+		Payload: &pb.Msg_BlockRequest{BlockRequest: &pb.BlockRequest{Hash: hash, RequesterId: f.selfID}},
+	})
+
+	select {
+	case block := <-wait:
+		return block, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("fetch parent %x: %w", hash, ctx.Err())
+	}
+}
+
+// OnBlockResponse delivers a BlockResponse received over the wire to any
+// goroutine blocked in FetchParent, and persists every block it carries.
+func (f *Fetcher) OnBlockResponse(resp *pb.BlockResponse) {
+	for _, block := range resp.Blocks {
+		if err := f.storage.Put(block); err != nil {
+			logger.Warnf("[SYNC] failed to store fetched block %x: %v", block.Hash, err)
+			continue
+		}
+		key := hex.EncodeToString(block.Hash)
+		f.mu.Lock()
+		waiters := f.pending[key]
+		delete(f.pending, key)
+		f.mu.Unlock()
+		for _, w := range waiters {
+			w <- block
+		}
+	}
+}
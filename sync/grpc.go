@@ -0,0 +1,25 @@
+package sync
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/wjbbig/go-hotstuff/proto"
+)
+
+// GRPCRequester returns a Downloader requester that dials peerAddr and
+// issues a single GetBlocks call for [start, end]. It is the default
+// requester wired into production Downloaders; tests inject their own to
+// avoid touching the network.
+func GRPCRequester() func(ctx context.Context, peerAddr string, start, end uint64) (*pb.BlockResponse, error) {
+	return func(ctx context.Context, peerAddr string, start, end uint64) (*pb.BlockResponse, error) {
+		conn, err := grpc.DialContext(ctx, peerAddr, grpc.WithInsecure(), grpc.WithBlock())
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+		client := pb.NewSyncClient(conn)
+		return client.GetBlocks(ctx, &pb.BlockRequest{StartHeight: start, EndHeight: end})
+	}
+}
@@ -0,0 +1,57 @@
+package sync
+
+import (
+	"context"
+
+	pb "github.com/wjbbig/go-hotstuff/proto"
+)
+
+// HeightBlockStore is the subset of BlockStorage a Server needs to answer a
+// height-range BlockRequest: BlockStorage only indexes blocks by hash, so a
+// range has to be resolved by walking ParentHash back from the chain tip.
+type HeightBlockStore interface {
+	BlockStore
+	GetLastBlockHash() []byte
+}
+
+// Server implements pb.SyncServer, the peer side of GRPCRequester: it walks
+// the local chain backward from its tip collecting every block whose height
+// falls in [req.StartHeight, req.EndHeight], then returns whatever it found
+// in ascending height order.
+type Server struct {
+	pb.UnimplementedSyncServer
+	storage HeightBlockStore
+}
+
+// NewServer returns a pb.SyncServer backed by storage, for registering
+// against a grpc.Server with pb.RegisterSyncServer.
+func NewServer(storage HeightBlockStore) *Server {
+	return &Server{storage: storage}
+}
+
+func (s *Server) GetBlocks(ctx context.Context, req *pb.BlockRequest) (*pb.BlockResponse, error) {
+	byHeight := make(map[uint64]*pb.Block)
+	for hash := s.storage.GetLastBlockHash(); len(hash) > 0; {
+		block, err := s.storage.Get(hash)
+		if err != nil || block == nil {
+			break
+		}
+		if block.Height < req.StartHeight {
+			break
+		}
+		if block.Height <= req.EndHeight {
+			byHeight[block.Height] = block
+		}
+		hash = block.ParentHash
+	}
+
+	resp := &pb.BlockResponse{}
+	for h := req.StartHeight; h <= req.EndHeight; h++ {
+		block, ok := byHeight[h]
+		if !ok {
+			break
+		}
+		resp.Blocks = append(resp.Blocks, block)
+	}
+	return resp, nil
+}
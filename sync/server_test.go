@@ -0,0 +1,63 @@
+package sync
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/wjbbig/go-hotstuff/proto"
+)
+
+// chainBlockStore is a memBlockStore that also tracks the chain tip, so
+// Server.GetBlocks has something to walk ParentHash back from.
+type chainBlockStore struct {
+	*memBlockStore
+	lastHash []byte
+}
+
+func (s *chainBlockStore) GetLastBlockHash() []byte {
+	return s.lastHash
+}
+
+func newChainBlockStore(height uint64) *chainBlockStore {
+	store := &chainBlockStore{memBlockStore: newMemBlockStore()}
+	var parent []byte
+	for h := uint64(0); h <= height; h++ {
+		block := &pb.Block{Hash: heightHash(h), ParentHash: parent, Height: h}
+		store.Put(block)
+		parent = block.Hash
+	}
+	store.lastHash = parent
+	return store
+}
+
+func TestServerGetBlocksReturnsContiguousRangeInAscendingOrder(t *testing.T) {
+	store := newChainBlockStore(20)
+	s := NewServer(store)
+
+	resp, err := s.GetBlocks(context.Background(), &pb.BlockRequest{StartHeight: 5, EndHeight: 10})
+	if err != nil {
+		t.Fatalf("GetBlocks: %v", err)
+	}
+	if len(resp.Blocks) != 6 {
+		t.Fatalf("expected 6 blocks, got %d", len(resp.Blocks))
+	}
+	for i, block := range resp.Blocks {
+		wantHeight := uint64(5 + i)
+		if block.Height != wantHeight {
+			t.Fatalf("block %d: expected height %d, got %d", i, wantHeight, block.Height)
+		}
+	}
+}
+
+func TestServerGetBlocksStopsAtLocalTip(t *testing.T) {
+	store := newChainBlockStore(5)
+	s := NewServer(store)
+
+	resp, err := s.GetBlocks(context.Background(), &pb.BlockRequest{StartHeight: 3, EndHeight: 100})
+	if err != nil {
+		t.Fatalf("GetBlocks: %v", err)
+	}
+	if len(resp.Blocks) != 3 {
+		t.Fatalf("expected blocks 3-5 only (3 blocks), got %d", len(resp.Blocks))
+	}
+}
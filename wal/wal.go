@@ -0,0 +1,81 @@
+// Package wal implements a write-ahead log for safety-critical consensus
+// state, modeled on tendermint's consensus/wal: every view-changing event is
+// appended as a length-prefixed, CRC-checked protobuf record before the
+// in-memory state it describes is allowed to influence a vote, so a crash
+// and restart can replay the log and recover without equivocating.
+package wal
+
+import pb "github.com/wjbbig/go-hotstuff/proto"
+
+// WAL is the interface ChainedHotStuff (and the other HotStuffImpl variants)
+// depend on, so tests can inject an in-memory implementation instead of
+// hitting the filesystem.
+type WAL interface {
+	// Write appends rec and fsyncs before returning.
+	Write(rec *pb.WALRecord) error
+	// ReadAll returns every record currently retained, oldest first. It is
+	// only meant to be called once, at startup, to replay state.
+	ReadAll() ([]*pb.WALRecord, error)
+	// Compact discards records for views that led to a block at or below
+	// committedHeight, since that state can no longer be needed for replay.
+	// The latest QcFormed record for each slot is kept regardless of view.
+	Compact(committedHeight uint64) error
+	// Close flushes and releases the underlying resources.
+	Close() error
+}
+
+// compactRecords drops records belonging to a view that a BlockCommitted
+// record in records shows already committed at or below committedHeight.
+// Keying the cutoff off BlockCommitted's own ViewNum (rather than only ever
+// discarding BlockCommitted records themselves) is what keeps
+// VoteSent/ViewEntered from a compacted view from accumulating forever:
+// neither can be needed for replay once the view that produced them has
+// been superseded by a later commit.
+//
+// QcFormed is the exception: the genericQC/lockQC/highQC it carries are
+// commonly formed in the very same view as the commit that supersedes it
+// (the happy path does all three in one update() call), so the view
+// cutoff can't be applied to QcFormed at all — the latest record per slot
+// is always kept, and an older one is dropped only because a later one for
+// that same slot has taken its place. Dropping every QcFormed record for the cutoff view
+// would erase the QC that view just formed, reverting a replayed replica
+// back to its genesis QC.
+func compactRecords(records []*pb.WALRecord, committedHeight uint64) []*pb.WALRecord {
+	var cutoff uint64
+	var haveCutoff bool
+	for _, rec := range records {
+		commit := rec.GetBlockCommitted()
+		if commit == nil || commit.Height > committedHeight {
+			continue
+		}
+		if !haveCutoff || rec.ViewNum > cutoff {
+			cutoff = rec.ViewNum
+			haveCutoff = true
+		}
+	}
+	if !haveCutoff {
+		return records
+	}
+
+	latestQC := make(map[string]*pb.WALRecord)
+	for _, rec := range records {
+		if qc := rec.GetQcFormed(); qc != nil {
+			latestQC[qc.Slot] = rec
+		}
+	}
+
+	kept := records[:0]
+	for _, rec := range records {
+		if qc := rec.GetQcFormed(); qc != nil {
+			if latestQC[qc.Slot] == rec {
+				kept = append(kept, rec)
+			}
+			continue
+		}
+		if rec.ViewNum <= cutoff {
+			continue
+		}
+		kept = append(kept, rec)
+	}
+	return kept
+}
@@ -0,0 +1,112 @@
+package wal
+
+import (
+	"testing"
+
+	pb "github.com/wjbbig/go-hotstuff/proto"
+)
+
+// TestMemWALReplaysInOrder checks that ReadAll hands back every record a
+// fresh memWAL was given, in the order it was written, so replayWAL can
+// reconstruct state deterministically.
+func TestMemWALReplaysInOrder(t *testing.T) {
+	w := NewMemWAL()
+	if err := w.Write(&pb.WALRecord{ViewNum: 1, Event: &pb.WALRecord_ViewEntered{ViewEntered: &pb.ViewEnteredEvent{ViewNum: 1}}}); err != nil {
+		t.Fatalf("write viewEntered: %v", err)
+	}
+	if err := w.Write(&pb.WALRecord{ViewNum: 1, Event: &pb.WALRecord_VoteSent{VoteSent: &pb.VoteSentEvent{BlockHash: []byte("b1")}}}); err != nil {
+		t.Fatalf("write voteSent: %v", err)
+	}
+	if err := w.Write(&pb.WALRecord{ViewNum: 1, Event: &pb.WALRecord_BlockCommitted{BlockCommitted: &pb.BlockCommittedEvent{BlockHash: []byte("b1"), Height: 1}}}); err != nil {
+		t.Fatalf("write blockCommitted: %v", err)
+	}
+
+	records, err := w.ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(records))
+	}
+	if records[0].GetViewEntered() == nil || records[1].GetVoteSent() == nil || records[2].GetBlockCommitted() == nil {
+		t.Fatalf("records came back out of order: %+v", records)
+	}
+}
+
+// TestMemWALCompactPrunesVoteAndViewRecordsForCommittedViews checks that
+// Compact no longer leaves VoteSent/ViewEntered records from an
+// already-committed view behind, which would otherwise grow the WAL
+// without bound despite Compact's own doc comment promising it wouldn't.
+func TestMemWALCompactPrunesVoteAndViewRecordsForCommittedViews(t *testing.T) {
+	w := NewMemWAL()
+	write := func(rec *pb.WALRecord) {
+		t.Helper()
+		if err := w.Write(rec); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	write(&pb.WALRecord{ViewNum: 1, Event: &pb.WALRecord_ViewEntered{ViewEntered: &pb.ViewEnteredEvent{ViewNum: 1}}})
+	write(&pb.WALRecord{ViewNum: 1, Event: &pb.WALRecord_VoteSent{VoteSent: &pb.VoteSentEvent{BlockHash: []byte("b1")}}})
+	write(&pb.WALRecord{ViewNum: 1, Event: &pb.WALRecord_BlockCommitted{BlockCommitted: &pb.BlockCommittedEvent{BlockHash: []byte("b1"), Height: 1}}})
+
+	write(&pb.WALRecord{ViewNum: 2, Event: &pb.WALRecord_ViewEntered{ViewEntered: &pb.ViewEnteredEvent{ViewNum: 2}}})
+	write(&pb.WALRecord{ViewNum: 2, Event: &pb.WALRecord_VoteSent{VoteSent: &pb.VoteSentEvent{BlockHash: []byte("b2")}}})
+
+	if err := w.Compact(1); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+
+	records, err := w.ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	for _, rec := range records {
+		if rec.ViewNum <= 1 {
+			t.Fatalf("expected every view-1 vote/view record to be pruned, found %+v", rec)
+		}
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected the 2 view-2 records to survive, got %d: %+v", len(records), records)
+	}
+}
+
+// TestMemWALCompactKeepsLatestQCFormedPerSlotAcrossTheCutoffView checks
+// that Compact never drops the current genericQC/lockQC/highQC record,
+// even when it was formed in the same (now-committed) view as the cutoff
+// — the common case, since all three QCs and the commit they produce
+// usually happen inside a single update() call for one view.
+func TestMemWALCompactKeepsLatestQCFormedPerSlotAcrossTheCutoffView(t *testing.T) {
+	w := NewMemWAL()
+	write := func(rec *pb.WALRecord) {
+		t.Helper()
+		if err := w.Write(rec); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	write(&pb.WALRecord{ViewNum: 1, Event: &pb.WALRecord_QcFormed{QcFormed: &pb.QCFormedEvent{Qc: &pb.QuorumCert{ViewNum: 1}, Slot: "highQC"}}})
+	write(&pb.WALRecord{ViewNum: 1, Event: &pb.WALRecord_QcFormed{QcFormed: &pb.QCFormedEvent{Qc: &pb.QuorumCert{ViewNum: 1}, Slot: "genericQC"}}})
+	write(&pb.WALRecord{ViewNum: 1, Event: &pb.WALRecord_QcFormed{QcFormed: &pb.QCFormedEvent{Qc: &pb.QuorumCert{ViewNum: 1}, Slot: "lockQC"}}})
+	write(&pb.WALRecord{ViewNum: 1, Event: &pb.WALRecord_BlockCommitted{BlockCommitted: &pb.BlockCommittedEvent{BlockHash: []byte("b1"), Height: 1}}})
+
+	if err := w.Compact(1); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+
+	records, err := w.ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	slots := make(map[string]bool)
+	for _, rec := range records {
+		if qc := rec.GetQcFormed(); qc != nil {
+			slots[qc.Slot] = true
+		}
+	}
+	for _, slot := range []string{"genericQC", "lockQC", "highQC"} {
+		if !slots[slot] {
+			t.Fatalf("expected %s's QcFormed record to survive compaction, records: %+v", slot, records)
+		}
+	}
+}